@@ -0,0 +1,161 @@
+// Copyright 2013 Beego Authors
+// Copyright 2014 The Macaron Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package captcha
+
+import (
+	"bytes"
+	"embed"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+//go:embed assets/audio/en/*.wav
+var audioAssets embed.FS
+
+const audioSampleRate = 8000
+
+// Audio is a rendered audio captcha challenge, ready to be streamed out as
+// an 8kHz mono 16-bit PCM WAV file.
+type Audio struct {
+	samples []int16
+}
+
+// NewAudio renders digits into an audio challenge for the requested
+// language, falling back to English if lang has no sample pack embedded.
+// Like NewImage, rendering is seeded from digits so it is reproducible, but
+// each digit's clip is additionally layered with reproducible noise and
+// separated by a randomized gap so no two captchas sound alike.
+func NewAudio(digits []byte, lang string) *Audio {
+	rng := newSipRNG(seedFromDigits(digits))
+	a := &Audio{}
+
+	for i, raw := range digits {
+		if d, ok := digitValue(raw); ok {
+			if clip, err := loadDigitClip(lang, d); err == nil {
+				a.samples = append(a.samples, addHiss(clip, rng)...)
+			}
+		}
+		if i != len(digits)-1 {
+			gapMillis := 200 + rng.Intn(400)
+			a.samples = append(a.samples, make([]int16, audioSampleRate*gapMillis/1000)...)
+		}
+	}
+
+	return a
+}
+
+// digitValue normalizes a raw challenge byte to a 0-9 digit value,
+// accepting either a legacy digit offset or a literal ASCII digit.
+// Non-digit challenge characters (letters, wordlist entries) have no
+// audio sample and are reported as not ok.
+func digitValue(c byte) (byte, bool) {
+	if c < 10 {
+		return c, true
+	}
+	if c >= '0' && c <= '9' {
+		return c - '0', true
+	}
+	return 0, false
+}
+
+// loadDigitClip loads the embedded PCM samples for a single digit, falling
+// back to the "en" pack when lang has no pack of its own.
+func loadDigitClip(lang string, digit byte) ([]int16, error) {
+	if digit > 9 {
+		return nil, fmt.Errorf("captcha: invalid digit %d", digit)
+	}
+	if len(lang) == 0 {
+		lang = "en"
+	}
+
+	data, err := audioAssets.ReadFile(fmt.Sprintf("assets/audio/%s/%d.wav", lang, digit))
+	if err != nil {
+		data, err = audioAssets.ReadFile(fmt.Sprintf("assets/audio/en/%d.wav", digit))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pcmSamples(data)
+}
+
+// pcmSamples extracts the 16-bit PCM samples out of a canonical WAV file.
+func pcmSamples(wav []byte) ([]int16, error) {
+	if len(wav) < 44 || string(wav[0:4]) != "RIFF" || string(wav[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("captcha: not a canonical WAV file")
+	}
+	data := wav[44:]
+	samples := make([]int16, len(data)/2)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(data[i*2:]))
+	}
+	return samples, nil
+}
+
+// addHiss layers faint reproducible background noise onto clip.
+func addHiss(clip []int16, rng *siprng) []int16 {
+	out := make([]int16, len(clip))
+	for i, s := range clip {
+		noise := int32(rng.Intn(400) - 200)
+		v := int32(s) + noise
+		switch {
+		case v > 32767:
+			v = 32767
+		case v < -32768:
+			v = -32768
+		}
+		out[i] = int16(v)
+	}
+	return out
+}
+
+// WriteTo writes the audio challenge as an 8kHz mono 16-bit PCM WAV stream.
+func (a *Audio) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+
+	dataSize := len(a.samples) * 2
+	buf.WriteString("RIFF")
+	writeUint32(&buf, uint32(36+dataSize))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	writeUint32(&buf, 16)
+	writeUint16(&buf, 1) // PCM
+	writeUint16(&buf, 1) // mono
+	writeUint32(&buf, audioSampleRate)
+	writeUint32(&buf, audioSampleRate*2) // byte rate
+	writeUint16(&buf, 2)                 // block align
+	writeUint16(&buf, 16)                // bits per sample
+	buf.WriteString("data")
+	writeUint32(&buf, uint32(dataSize))
+	for _, s := range a.samples {
+		writeUint16(&buf, uint16(s))
+	}
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+func writeUint32(buf *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+func writeUint16(buf *bytes.Buffer, v uint16) {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], v)
+	buf.Write(b[:])
+}
@@ -17,23 +17,27 @@
 package captcha
 
 import (
-	"fmt"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"html/template"
-	"path"
 	"strings"
+	"sync"
+	"time"
 
-	"landzero.net/x/com"
 	"landzero.net/x/net/web"
 	"landzero.net/x/net/web/cache"
 )
 
-var (
-	defaultChars = []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
-)
-
 // Captcha represents a captcha service.
 type Captcha struct {
-	store            cache.Cache
+	store            Store
+	rlCache          cache.Cache
+	rlMu             sync.Mutex
+	provider         Provider
+	RateLimit        RateLimit
+	CharSet          CharSet
+	Wordlist         []string
 	SubURL           string
 	URLPrefix        string
 	FieldIDName      string
@@ -50,36 +54,39 @@ func (c *Captcha) key(id string) string {
 	return c.CachePrefix + id
 }
 
-// generate rand chars with default chars
-func (c *Captcha) genRandChars() string {
-	return string(com.RandomCreateBytes(c.ChallengeNums, defaultChars...))
-}
-
-// CreateHTML outputs HTML for display and fetch new captcha images.
+// CreateHTML outputs HTML for display and fetch new captcha images. It
+// delegates to the underlying Provider, so existing templates keep working
+// regardless of which backend is configured.
 func (c *Captcha) CreateHTML() template.HTML {
-	value, err := c.CreateCaptcha()
-	if err != nil {
-		panic(fmt.Errorf("fail to create captcha: %v", err))
-	}
-	return template.HTML(fmt.Sprintf(`<input type="hidden" name="%[1]s" value="%[2]s">
-	<a class="captcha" href="javascript:" tabindex="-1">
-		<img onclick="this.src=('%[3]s%[4]s%[2]s.png?reload='+(new Date()).getTime())" class="captcha-img" src="%[3]s%[4]s%[2]s.png">
-	</a>`, c.FieldIDName, value, c.SubURL, c.URLPrefix))
+	return c.provider.RenderHTML()
 }
 
-// CreateCaptcha create a new captcha id
+// CreateCaptcha create a new captcha id. The id is 20 bytes of crypto/rand
+// output, base64-url encoded, which keeps it URL-safe and unguessable.
 func (c *Captcha) CreateCaptcha() (string, error) {
-	id := string(com.RandomCreateBytes(15))
-	if err := c.store.Put(c.key(id), c.genRandChars(), c.Expiration); err != nil {
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	id := base64.RawURLEncoding.EncodeToString(raw)
+
+	value := encodeChallenge(challengeVersion, c.genRandChars())
+	if err := c.store.Set(c.key(id), value, time.Duration(c.Expiration)*time.Second); err != nil {
 		return "", err
 	}
 	return id, nil
 }
 
-// VerifyReq verify from a request
+// VerifyReq verify from a request. It delegates to the configured
+// Provider, so third-party backends (reCAPTCHA, hCaptcha, Turnstile,
+// mCaptcha) are checked against their own siteverify response rather than
+// the built-in image/digit challenge.
 func (c *Captcha) VerifyReq(req web.Request) bool {
-	req.ParseForm()
-	return c.Verify(req.Form.Get(c.FieldIDName), req.Form.Get(c.FieldCaptchaName))
+	ok, err := c.provider.Verify(req)
+	if err != nil {
+		return false
+	}
+	return ok
 }
 
 // Verify direct verify id and challenge string
@@ -88,30 +95,40 @@ func (c *Captcha) Verify(id string, challenge string) bool {
 		return false
 	}
 
-	var chars string
+	if !c.checkVerifyAttempt(id) {
+		return false
+	}
 
 	key := c.key(id)
 
-	if v, ok := c.store.Get(key).(string); ok {
-		chars = v
-	} else {
+	raw, err := c.store.Get(key)
+	if err != nil {
+		return false
+	}
+	value, version, ok := decodeChallenge(raw)
+	if !ok {
 		return false
 	}
 
 	defer c.store.Delete(key)
 
-	if len(chars) != len(challenge) {
+	if len(value) != len(challenge) {
 		return false
 	}
 
-	// verify challenge
-	for i, c := range []byte(chars) {
-		if c != challenge[i]-48 {
-			return false
+	// Verify in constant time so a timing attack can't leak how many
+	// leading characters an attacker has already guessed correctly.
+	if version < challengeVersion {
+		// Legacy entries store raw digit offsets (0-9), not the literal
+		// ASCII challenge.
+		got := make([]byte, len(challenge))
+		for i := 0; i < len(challenge); i++ {
+			got[i] = challenge[i] - 48
 		}
+		return subtle.ConstantTimeCompare([]byte(value), got) == 1
 	}
 
-	return true
+	return subtle.ConstantTimeCompare([]byte(strings.ToLower(value)), []byte(strings.ToLower(challenge))) == 1
 }
 
 // Options captcha options
@@ -134,6 +151,24 @@ type Options struct {
 	Expiration int64
 	// Cache key prefix captcha characters. Default is "captcha_".
 	CachePrefix string
+	// Type selects the Provider backing the captcha. Default is TypeImage,
+	// the built-in image challenge.
+	Type Type
+	// Recaptcha configures the TypeRecaptcha provider.
+	Recaptcha RemoteOptions
+	// HCaptcha configures the TypeHCaptcha provider.
+	HCaptcha RemoteOptions
+	// Turnstile configures the TypeTurnstile provider.
+	Turnstile RemoteOptions
+	// MCaptcha configures the TypeMCaptcha provider.
+	MCaptcha RemoteOptions
+	// RateLimit bounds captcha issuance and verify attempts. Unset fields
+	// disable their respective limit.
+	RateLimit RateLimit
+	// CharSet selects what a challenge is made of. Default is DigitsOnly.
+	CharSet CharSet
+	// Wordlist is the pool of words drawn from when CharSet is Wordlist.
+	Wordlist []string
 }
 
 func prepareOptions(options []Options) Options {
@@ -175,9 +210,18 @@ func prepareOptions(options []Options) Options {
 	return opt
 }
 
-// NewCaptcha initializes and returns a captcha with given options.
-func NewCaptcha(opt Options) *Captcha {
-	return &Captcha{
+// NewCaptcha initializes and returns a captcha backed by store, configured
+// by opt. Taking store explicitly (rather than reaching for a shared cache
+// internally) is what lets a *Captcha be used standalone, outside of
+// Captchaer -- see NewMemoryStore and NewRedisStore. It panics if opt.Type
+// names an unknown Provider, the same way prepareOptions' siblings fail
+// fast on misconfiguration.
+func NewCaptcha(store Store, opt Options) *Captcha {
+	c := &Captcha{
+		store:            store,
+		RateLimit:        opt.RateLimit,
+		CharSet:          opt.CharSet,
+		Wordlist:         opt.Wordlist,
 		SubURL:           opt.SubURL,
 		URLPrefix:        opt.URLPrefix,
 		FieldIDName:      opt.FieldIDName,
@@ -188,45 +232,25 @@ func NewCaptcha(opt Options) *Captcha {
 		Expiration:       opt.Expiration,
 		CachePrefix:      opt.CachePrefix,
 	}
+
+	provider, err := newProvider(c, opt)
+	if err != nil {
+		panic(err)
+	}
+	c.provider = provider
+
+	return c
 }
 
 // Captchaer is a middleware that maps a captcha.Captcha service into the Macaron handler chain.
 // An single variadic captcha.Options struct can be optionally provided to configure.
 // This should be register after cache.Cacher.
 func Captchaer(options ...Options) web.Handler {
-	return func(ctx *web.Context, cache cache.Cache) {
-		cpt := NewCaptcha(prepareOptions(options))
-		cpt.store = cache
-
-		if strings.HasPrefix(ctx.Req.URL.Path, cpt.URLPrefix) {
-			var chars string
-			id := path.Base(ctx.Req.URL.Path)
-			if i := strings.Index(id, "."); i > -1 {
-				id = id[:i]
-			}
-			key := cpt.key(id)
-
-			// Reload captcha.
-			if len(ctx.Query("reload")) > 0 {
-				chars = cpt.genRandChars()
-				if err := cpt.store.Put(key, chars, cpt.Expiration); err != nil {
-					ctx.Status(500)
-					ctx.Write([]byte("captcha reload error"))
-					panic(fmt.Errorf("reload captcha: %v", err))
-				}
-			} else {
-				if v, ok := cpt.store.Get(key).(string); ok {
-					chars = v
-				} else {
-					ctx.Status(404)
-					ctx.Write([]byte("captcha not found"))
-					return
-				}
-			}
-
-			if _, err := NewImage([]byte(chars), cpt.StdWidth, cpt.StdHeight).WriteTo(ctx.Resp); err != nil {
-				panic(fmt.Errorf("write captcha: %v", err))
-			}
+	return func(ctx *web.Context, c cache.Cache) {
+		cpt := NewCaptcha(NewCacheStore(c), prepareOptions(options))
+		cpt.rlCache = c
+
+		if server, ok := cpt.provider.(AssetServer); ok && server.ServeAsset(ctx) {
 			return
 		}
 
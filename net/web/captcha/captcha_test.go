@@ -0,0 +1,94 @@
+// Copyright 2013 Beego Authors
+// Copyright 2014 The Macaron Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package captcha
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// mapStore is a minimal Store backed by a map, used to exercise Verify
+// without wiring up a real cache.
+type mapStore struct {
+	m map[string][]byte
+}
+
+func (s *mapStore) Set(id string, digits []byte, ttl time.Duration) error {
+	if s.m == nil {
+		s.m = make(map[string][]byte)
+	}
+	s.m[id] = digits
+	return nil
+}
+
+func (s *mapStore) Get(id string) ([]byte, error) {
+	v, ok := s.m[id]
+	if !ok {
+		return nil, fmt.Errorf("captcha: no such id %q", id)
+	}
+	return v, nil
+}
+
+func (s *mapStore) Delete(id string) error {
+	delete(s.m, id)
+	return nil
+}
+
+func TestVerifyCurrentVersion(t *testing.T) {
+	store := &mapStore{}
+	c := &Captcha{store: store}
+	store.Set(c.key("id"), encodeChallenge(challengeVersion, "aB3xY9"), time.Minute)
+
+	if !c.Verify("id", "ab3xy9") {
+		t.Fatal("expected case-insensitive match against a version 2 entry")
+	}
+}
+
+func TestVerifyLegacyVersion1(t *testing.T) {
+	store := &mapStore{}
+	c := &Captcha{store: store}
+	// Pre-CharSet entries stored raw digit offsets (0-9), not the literal
+	// ASCII challenge.
+	store.Set(c.key("id"), encodeChallenge(1, string([]byte{1, 2, 3})), time.Minute)
+
+	if !c.Verify("id", "123") {
+		t.Fatal("expected legacy digit-offset entry to verify against \"123\"")
+	}
+}
+
+func TestVerifyLegacyVersion1Mismatch(t *testing.T) {
+	store := &mapStore{}
+	c := &Captcha{store: store}
+	store.Set(c.key("id"), encodeChallenge(1, string([]byte{1, 2, 3})), time.Minute)
+
+	if c.Verify("id", "124") {
+		t.Fatal("expected legacy digit-offset entry not to verify against a wrong guess")
+	}
+}
+
+func TestVerifyDeletesOnSuccess(t *testing.T) {
+	store := &mapStore{}
+	c := &Captcha{store: store}
+	store.Set(c.key("id"), encodeChallenge(challengeVersion, "abc123"), time.Minute)
+
+	if !c.Verify("id", "abc123") {
+		t.Fatal("expected first verify to succeed")
+	}
+	if c.Verify("id", "abc123") {
+		t.Fatal("expected a solved captcha to be consumed, not reusable")
+	}
+}
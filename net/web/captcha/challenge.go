@@ -0,0 +1,46 @@
+// Copyright 2013 Beego Authors
+// Copyright 2014 The Macaron Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package captcha
+
+// challengeVersion is encoded into every stored challenge so old entries
+// (stored before CharSet existed, as a bare digit-offset string) keep
+// verifying correctly against the legacy comparison.
+const challengeVersion = 2
+
+// challengeEntry is the shape Captcha used to store in cache.Cache directly
+// before the Store interface existed. CacheStore still recognizes it so
+// captchas created before the upgrade keep verifying.
+type challengeEntry struct {
+	Version int
+	Value   string
+}
+
+// encodeChallenge packs a version and challenge string into the []byte
+// format Store implementations persist.
+func encodeChallenge(version int, value string) []byte {
+	b := make([]byte, 1+len(value))
+	b[0] = byte(version)
+	copy(b[1:], value)
+	return b
+}
+
+// decodeChallenge unpacks bytes produced by encodeChallenge.
+func decodeChallenge(raw []byte) (value string, version int, ok bool) {
+	if len(raw) == 0 {
+		return "", 0, false
+	}
+	return string(raw[1:]), int(raw[0]), true
+}
@@ -0,0 +1,72 @@
+// Copyright 2013 Beego Authors
+// Copyright 2014 The Macaron Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package captcha
+
+import (
+	"crypto/rand"
+	"math/big"
+
+	"landzero.net/x/com"
+)
+
+// CharSet selects what a challenge is made of.
+type CharSet int
+
+const (
+	// DigitsOnly draws challenges from 0-9. This is the default.
+	DigitsOnly CharSet = iota
+	// Alphanumeric draws challenges from 0-9 and A-Za-z.
+	Alphanumeric
+	// LettersOnly draws challenges from A-Za-z.
+	LettersOnly
+	// Wordlist draws a whole challenge string from Options.Wordlist instead
+	// of assembling one character at a time.
+	Wordlist
+)
+
+var (
+	digitChars        = []byte("0123456789")
+	letterChars       = []byte("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz")
+	alphanumericChars = append(append([]byte{}, digitChars...), letterChars...)
+)
+
+// genRandChars returns a new challenge string drawn according to c.CharSet.
+func (c *Captcha) genRandChars() string {
+	switch c.CharSet {
+	case Wordlist:
+		if len(c.Wordlist) == 0 {
+			break
+		}
+		return c.Wordlist[randIndex(len(c.Wordlist))]
+	case Alphanumeric:
+		return string(com.RandomCreateBytes(c.ChallengeNums, alphanumericChars...))
+	case LettersOnly:
+		return string(com.RandomCreateBytes(c.ChallengeNums, letterChars...))
+	}
+	return string(com.RandomCreateBytes(c.ChallengeNums, digitChars...))
+}
+
+// randIndex returns a cryptographically random number in [0, n).
+func randIndex(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0
+	}
+	return int(i.Int64())
+}
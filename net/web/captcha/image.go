@@ -0,0 +1,182 @@
+// Copyright 2013 Beego Authors
+// Copyright 2014 The Macaron Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package captcha
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+)
+
+const (
+	stdWidth  = 240
+	stdHeight = 80
+)
+
+// Image is a rendered captcha challenge, ready to be streamed out as PNG.
+type Image struct {
+	*image.Paletted
+}
+
+// NewImage renders chars into a width x height captcha image. chars holds
+// one byte per glyph: either a legacy digit offset (0-9, for captchas
+// created before alphanumeric/wordlist support) or a literal ASCII
+// character. Rendering is driven entirely by a siprng seeded from chars, so
+// calling NewImage again with the same chars reproduces the exact same
+// pixels -- this is what lets the image handler answer with a long-lived,
+// cacheable ETag instead of a fresh random image on every request.
+func NewImage(chars []byte, width, height int) *Image {
+	rng := newSipRNG(seedFromDigits(chars))
+
+	palette := color.Palette{
+		color.RGBA{0xFF, 0xFF, 0xFF, 0xFF},
+		color.RGBA{0x20, 0x20, 0x20, 0xFF},
+	}
+	for i := 0; i < 12; i++ {
+		palette = append(palette, color.RGBA{
+			R: uint8(100 + rng.Intn(120)),
+			G: uint8(100 + rng.Intn(120)),
+			B: uint8(100 + rng.Intn(120)),
+			A: 0xFF,
+		})
+	}
+
+	m := &Image{image.NewPaletted(image.Rect(0, 0, width, height), palette)}
+	m.drawBackground(rng)
+	m.drawGlyphs(rng, chars)
+	m.drawNoise(rng)
+
+	return m
+}
+
+// seedFromDigits derives a 16-byte siprng seed from the captcha's raw
+// challenge bytes.
+func seedFromDigits(digits []byte) []byte {
+	seed := make([]byte, 16)
+	for i, d := range digits {
+		seed[i%16] ^= d*31 + byte(i)
+	}
+	return seed
+}
+
+// glyphKey normalizes a raw challenge byte to the key used in glyphFont: a
+// legacy digit offset (0-9) becomes its ASCII digit, and letters are
+// upper-cased so either case renders the same glyph.
+func glyphKey(c byte) byte {
+	if c < 10 {
+		return '0' + c
+	}
+	if c >= 'a' && c <= 'z' {
+		return c - ('a' - 'A')
+	}
+	return c
+}
+
+func (m *Image) drawBackground(rng *siprng) {
+	bounds := m.Bounds()
+	bgColorIndex := uint8(0)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			m.SetColorIndex(x, y, bgColorIndex)
+		}
+	}
+
+	// A handful of wavy background lines, dchest-style.
+	for line := 0; line < 3; line++ {
+		amplitude := 4 + rng.Float64()*6
+		period := 30 + rng.Float64()*40
+		phase := rng.Float64() * math.Pi * 2
+		yOffset := bounds.Min.Y + rng.Intn(bounds.Dy())
+		colorIndex := uint8(2 + rng.Intn(len(m.Palette)-2))
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			y := yOffset + int(amplitude*math.Sin(float64(x)/period+phase))
+			if y >= bounds.Min.Y && y < bounds.Max.Y {
+				m.SetColorIndex(x, y, colorIndex)
+			}
+		}
+	}
+}
+
+func (m *Image) drawGlyphs(rng *siprng, chars []byte) {
+	if len(chars) == 0 {
+		return
+	}
+
+	bounds := m.Bounds()
+	cellWidth := bounds.Dx() / len(chars)
+	scale := cellWidth / 8
+	if scale < 1 {
+		scale = 1
+	}
+
+	for i, c := range chars {
+		glyph, ok := glyphFont[glyphKey(c)]
+		if !ok {
+			continue
+		}
+
+		baseX := bounds.Min.X + i*cellWidth + cellWidth/2 - (5*scale)/2
+		baseY := bounds.Min.Y + bounds.Dy()/2 - (7*scale)/2
+
+		// Per-digit warp: a small sinusoidal horizontal shift and a random
+		// vertical jitter, reproducible because rng is seeded from digits.
+		amplitude := rng.Float64() * float64(scale)
+		period := 6 + rng.Float64()*6
+		jitterY := rng.Intn(scale*2) - scale
+
+		for row := 0; row < 7; row++ {
+			bits := glyph[row]
+			for col := 0; col < 5; col++ {
+				if bits&(1<<(4-uint(col))) == 0 {
+					continue
+				}
+				for sy := 0; sy < scale; sy++ {
+					y := baseY + row*scale + sy + jitterY
+					warp := int(amplitude * math.Sin(float64(y)/period))
+					for sx := 0; sx < scale; sx++ {
+						x := baseX + col*scale + sx + warp
+						if (image.Point{x, y}.In(bounds)) {
+							m.SetColorIndex(x, y, 1)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func (m *Image) drawNoise(rng *siprng) {
+	bounds := m.Bounds()
+	dots := bounds.Dx() * bounds.Dy() / 40
+	for i := 0; i < dots; i++ {
+		x := bounds.Min.X + rng.Intn(bounds.Dx())
+		y := bounds.Min.Y + rng.Intn(bounds.Dy())
+		m.SetColorIndex(x, y, uint8(2+rng.Intn(len(m.Palette)-2)))
+	}
+}
+
+// WriteTo encodes the image as PNG and writes it to w.
+func (m *Image) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m.Paletted); err != nil {
+		return 0, err
+	}
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
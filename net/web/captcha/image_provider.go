@@ -0,0 +1,156 @@
+// Copyright 2013 Beego Authors
+// Copyright 2014 The Macaron Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package captcha
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"path"
+	"strings"
+	"time"
+
+	"landzero.net/x/net/web"
+)
+
+// imageProvider is the built-in, self-hosted image challenge. It is the
+// default Provider and the only one that also implements AssetServer, since
+// it needs to serve the challenge image itself.
+type imageProvider struct {
+	c *Captcha
+}
+
+func (p *imageProvider) RenderHTML() template.HTML {
+	c := p.c
+	value, err := c.CreateCaptcha()
+	if err != nil {
+		panic(fmt.Errorf("fail to create captcha: %v", err))
+	}
+	return template.HTML(fmt.Sprintf(`<input type="hidden" name="%[1]s" value="%[2]s">
+	<a class="captcha" href="javascript:" tabindex="-1">
+		<img onclick="this.src=('%[3]s%[4]s%[2]s.png?reload='+(new Date()).getTime())" class="captcha-img" src="%[3]s%[4]s%[2]s.png">
+	</a>
+	<a class="captcha-audio" href="javascript:" tabindex="-1">
+		<audio controls preload="none" src="%[3]s%[4]s%[2]s.wav"></audio>
+	</a>`, c.FieldIDName, value, c.SubURL, c.URLPrefix))
+}
+
+func (p *imageProvider) Verify(req web.Request) (bool, error) {
+	req.ParseForm()
+	c := p.c
+	return c.Verify(req.Form.Get(c.FieldIDName), req.Form.Get(c.FieldCaptchaName)), nil
+}
+
+// ServeAsset serves the challenge image at <URLPrefix><id>.png and the
+// challenge audio at <URLPrefix><id>.wav, regenerating the digits when the
+// request carries a "reload" query parameter.
+func (p *imageProvider) ServeAsset(ctx *web.Context) bool {
+	c := p.c
+	if !strings.HasPrefix(ctx.Req.URL.Path, c.URLPrefix) {
+		return false
+	}
+
+	if !c.checkIssuance(ctx.Req) {
+		ctx.Status(429)
+		ctx.Write([]byte("too many captcha requests"))
+		return true
+	}
+
+	base := path.Base(ctx.Req.URL.Path)
+	ext := path.Ext(base)
+	id := strings.TrimSuffix(base, ext)
+	key := c.key(id)
+
+	var chars string
+	if len(ctx.Query("reload")) > 0 {
+		chars = c.genRandChars()
+		value := encodeChallenge(challengeVersion, chars)
+		if err := c.store.Set(key, value, time.Duration(c.Expiration)*time.Second); err != nil {
+			ctx.Status(500)
+			ctx.Write([]byte("captcha reload error"))
+			panic(fmt.Errorf("reload captcha: %v", err))
+		}
+	} else {
+		raw, err := c.store.Get(key)
+		if err != nil {
+			ctx.Status(404)
+			ctx.Write([]byte("captcha not found"))
+			return true
+		}
+		value, _, ok := decodeChallenge(raw)
+		if !ok {
+			ctx.Status(404)
+			ctx.Write([]byte("captcha not found"))
+			return true
+		}
+		chars = value
+	}
+
+	if ext == ".wav" {
+		p.serveAudio(ctx, id, chars)
+	} else {
+		p.serveImage(ctx, id, chars)
+	}
+	return true
+}
+
+// serveImage writes the PNG challenge image. It is a pure function of
+// id+chars, so it can be served with a strong ETag: a reload bumps chars
+// (and thus the ETag), everything else is free to sit behind a CDN
+// indefinitely.
+func (p *imageProvider) serveImage(ctx *web.Context, id, chars string) {
+	c := p.c
+	etag := `"` + challengeETag(id, chars) + `"`
+	ctx.Resp.Header().Set("ETag", etag)
+	ctx.Resp.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	if ctx.Req.Header.Get("If-None-Match") == etag {
+		ctx.Status(304)
+		return
+	}
+
+	if _, err := NewImage([]byte(chars), c.StdWidth, c.StdHeight).WriteTo(ctx.Resp); err != nil {
+		panic(fmt.Errorf("write captcha: %v", err))
+	}
+}
+
+// serveAudio writes the WAV challenge audio for the language named by the
+// "lang" query parameter, falling back to English.
+func (p *imageProvider) serveAudio(ctx *web.Context, id, chars string) {
+	lang := ctx.Query("lang")
+
+	etag := `"` + challengeETag(id, chars+"|"+lang) + `"`
+	ctx.Resp.Header().Set("ETag", etag)
+	ctx.Resp.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	ctx.Resp.Header().Set("Content-Type", "audio/wav")
+	if ctx.Req.Header.Get("If-None-Match") == etag {
+		ctx.Status(304)
+		return
+	}
+
+	if _, err := NewAudio([]byte(chars), lang).WriteTo(ctx.Resp); err != nil {
+		panic(fmt.Errorf("write captcha audio: %v", err))
+	}
+}
+
+// challengeETag derives a short, stable ETag from a captcha's id and its
+// rendered content key (digits, optionally combined with other render
+// parameters such as language).
+func challengeETag(id, content string) string {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	h.Write([]byte(content))
+	return fmt.Sprintf("%x", h.Sum64())
+}
@@ -0,0 +1,80 @@
+// Copyright 2013 Beego Authors
+// Copyright 2014 The Macaron Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package captcha
+
+import (
+	"fmt"
+	"html/template"
+
+	"landzero.net/x/net/web"
+)
+
+// Type identifies which CAPTCHA backend a Captcha instance talks to.
+type Type string
+
+const (
+	// TypeImage is the built-in, self-hosted image challenge. This is the
+	// default when Options.Type is left empty.
+	TypeImage Type = "image"
+	// TypeRecaptcha verifies challenges against Google reCAPTCHA v2/v3.
+	TypeRecaptcha Type = "recaptcha"
+	// TypeHCaptcha verifies challenges against hCaptcha.
+	TypeHCaptcha Type = "hcaptcha"
+	// TypeTurnstile verifies challenges against Cloudflare Turnstile.
+	TypeTurnstile Type = "turnstile"
+	// TypeMCaptcha verifies challenges against a self-hosted mCaptcha instance.
+	TypeMCaptcha Type = "mcaptcha"
+)
+
+// Provider is implemented by every CAPTCHA backend, built-in or third-party.
+// A Captcha owns exactly one Provider, selected by Options.Type.
+type Provider interface {
+	// RenderHTML returns the markup used to embed the challenge widget in a
+	// page. It is what CreateHTML ends up calling.
+	RenderHTML() template.HTML
+	// Verify checks the challenge response carried by req and reports
+	// whether it solves the challenge.
+	Verify(req web.Request) (bool, error)
+}
+
+// AssetServer is implemented by providers that host their own assets, such
+// as the built-in image and audio challenges. Captchaer gives these
+// providers first look at a request before falling through to mapping the
+// *Captcha into the handler chain.
+type AssetServer interface {
+	// ServeAsset writes a response for ctx if it recognizes the request
+	// path, and reports whether it did so.
+	ServeAsset(ctx *web.Context) bool
+}
+
+// newProvider builds the Provider selected by opt.Type for the given
+// Captcha, defaulting to the built-in image challenge.
+func newProvider(c *Captcha, opt Options) (Provider, error) {
+	switch opt.Type {
+	case "", TypeImage:
+		return &imageProvider{c: c}, nil
+	case TypeRecaptcha:
+		return newRemoteProvider(TypeRecaptcha, opt.Recaptcha), nil
+	case TypeHCaptcha:
+		return newRemoteProvider(TypeHCaptcha, opt.HCaptcha), nil
+	case TypeTurnstile:
+		return newRemoteProvider(TypeTurnstile, opt.Turnstile), nil
+	case TypeMCaptcha:
+		return newRemoteProvider(TypeMCaptcha, opt.MCaptcha), nil
+	default:
+		return nil, fmt.Errorf("captcha: unknown provider type %q", opt.Type)
+	}
+}
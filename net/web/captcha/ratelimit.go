@@ -0,0 +1,107 @@
+// Copyright 2013 Beego Authors
+// Copyright 2014 The Macaron Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package captcha
+
+import (
+	"net"
+
+	"landzero.net/x/net/web"
+)
+
+// RateLimit bounds the two cheapest ways to abuse a captcha: hammering the
+// image handler for unlimited fresh renders, and brute-forcing the digit
+// space by repeatedly verifying against one stored ID.
+type RateLimit struct {
+	// MaxIssuancePerMinute caps how many new or reloaded images a single
+	// client may request per minute. Zero disables the limit.
+	MaxIssuancePerMinute int
+	// MaxVerifyAttemptsPerID caps how many times a single captcha ID may be
+	// checked before its stored digits are discarded outright. Zero
+	// disables the limit.
+	MaxVerifyAttemptsPerID int
+	// ClientKeyFunc identifies the client behind a request. Default is the
+	// request's remote IP.
+	ClientKeyFunc func(req web.Request) string
+}
+
+// defaultClientKeyFunc keys a client by remote IP, stripping the port.
+func defaultClientKeyFunc(req web.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+func (r RateLimit) clientKey(req web.Request) string {
+	if r.ClientKeyFunc != nil {
+		return r.ClientKeyFunc(req)
+	}
+	return defaultClientKeyFunc(req)
+}
+
+// incr bumps the counter at key, creating it with the given TTL on first
+// use, and reports its new value. cache.Cache has no atomic
+// increment-or-create primitive -- Incr fails outright on a key that
+// doesn't exist yet -- so two callers racing on a cold key could previously
+// both see that failure and both Put a losing first-hit value, dropping an
+// increment right at the rate-limit boundary. rlMu serializes the
+// check-then-act sequence within this process, which is where Captchaer's
+// rlCache is actually shared from, closing that race.
+func (c *Captcha) incr(key string, ttl int64) int {
+	c.rlMu.Lock()
+	defer c.rlMu.Unlock()
+
+	if err := c.rlCache.Incr(key); err != nil {
+		// Key doesn't exist yet (or isn't an int): seed it.
+		c.rlCache.Put(key, 1, ttl)
+		return 1
+	}
+	if v, ok := c.rlCache.Get(key).(int); ok {
+		return v
+	}
+	return 1
+}
+
+// checkIssuance enforces RateLimit.MaxIssuancePerMinute for the client
+// behind req, incrementing its per-minute counter as a side effect. It
+// reports whether the request is still within budget.
+func (c *Captcha) checkIssuance(req web.Request) bool {
+	limit := c.RateLimit.MaxIssuancePerMinute
+	if limit <= 0 || c.rlCache == nil {
+		return true
+	}
+	key := c.key("issue_" + c.RateLimit.clientKey(req))
+	return c.incr(key, 60) <= limit
+}
+
+// checkVerifyAttempt enforces RateLimit.MaxVerifyAttemptsPerID for a single
+// captcha id, incrementing its attempt counter as a side effect. Once the
+// limit is exceeded it deletes the stored digits so the id can never be
+// solved, even by an attacker who already guessed them.
+func (c *Captcha) checkVerifyAttempt(id string) bool {
+	limit := c.RateLimit.MaxVerifyAttemptsPerID
+	if limit <= 0 || c.rlCache == nil {
+		return true
+	}
+	key := c.key("attempts_" + id)
+	if c.incr(key, c.Expiration) > limit {
+		c.store.Delete(c.key(id))
+		c.rlCache.Delete(key)
+		return false
+	}
+	return true
+}
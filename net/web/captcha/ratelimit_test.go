@@ -0,0 +1,105 @@
+// Copyright 2013 Beego Authors
+// Copyright 2014 The Macaron Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package captcha
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// fakeCache is a minimal cache.Cache backed by a plain map, used to exercise
+// incr's locking without wiring up a real cache backend. It deliberately
+// mirrors cache.Cache's read-modify-write Incr (error on a cold key) rather
+// than an atomic one, so the test is actually exercising rlMu.
+type fakeCache struct {
+	mu sync.Mutex
+	m  map[string]int
+}
+
+func (f *fakeCache) Put(key string, val interface{}, timeout int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.m == nil {
+		f.m = make(map[string]int)
+	}
+	n, _ := val.(int)
+	f.m[key] = n
+	return nil
+}
+
+func (f *fakeCache) Get(key string) interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.m[key]
+}
+
+func (f *fakeCache) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.m, key)
+	return nil
+}
+
+func (f *fakeCache) Incr(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.m[key]; !ok {
+		return fmt.Errorf("fakeCache: no such key %q", key)
+	}
+	f.m[key]++
+	return nil
+}
+
+func (f *fakeCache) Decr(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.m[key]--
+	return nil
+}
+
+func (f *fakeCache) IsExist(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.m[key]
+	return ok
+}
+
+func (f *fakeCache) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.m = make(map[string]int)
+	return nil
+}
+
+func TestIncrConcurrentDoesNotLoseUpdates(t *testing.T) {
+	c := &Captcha{rlCache: &fakeCache{}}
+
+	const goroutines = 50
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			c.incr("key", 60)
+		}()
+	}
+	wg.Wait()
+
+	if got := c.rlCache.Get("key"); got != goroutines {
+		t.Fatalf("counter = %v, want %d (an update was lost to the cold-key race)", got, goroutines)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2013 Beego Authors
+// Copyright 2014 The Macaron Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package captcha
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// siprng is a deterministic pseudo-random source built on the SipHash-2-4
+// permutation. Seeding it from a captcha's own digits makes every render of
+// that captcha produce identical pixels, which is what lets the image
+// handler answer with a long-lived, cacheable ETag.
+type siprng struct {
+	v0, v1, v2, v3 uint64
+}
+
+// newSipRNG seeds a siprng from a 16-byte key.
+func newSipRNG(key []byte) *siprng {
+	var k [16]byte
+	copy(k[:], key)
+	k0 := binary.LittleEndian.Uint64(k[0:8])
+	k1 := binary.LittleEndian.Uint64(k[8:16])
+	return &siprng{
+		v0: k0 ^ 0x736f6d6570736575,
+		v1: k1 ^ 0x646f72616e646f6d,
+		v2: k0 ^ 0x6c7967656e657261,
+		v3: k1 ^ 0x7465646279746573,
+	}
+}
+
+// sipRound runs a single SipHash mix round over the PRNG state.
+func (s *siprng) sipRound() {
+	s.v0 += s.v1
+	s.v1 = bits.RotateLeft64(s.v1, 13)
+	s.v1 ^= s.v0
+	s.v0 = bits.RotateLeft64(s.v0, 32)
+	s.v2 += s.v3
+	s.v3 = bits.RotateLeft64(s.v3, 16)
+	s.v3 ^= s.v2
+	s.v0 += s.v3
+	s.v3 = bits.RotateLeft64(s.v3, 21)
+	s.v3 ^= s.v0
+	s.v2 += s.v1
+	s.v1 = bits.RotateLeft64(s.v1, 17)
+	s.v1 ^= s.v2
+	s.v2 = bits.RotateLeft64(s.v2, 32)
+}
+
+// Uint64 returns the next value in the pseudo-random stream.
+func (s *siprng) Uint64() uint64 {
+	s.v2 ^= 0xee
+	for i := 0; i < 4; i++ {
+		s.sipRound()
+	}
+	return s.v0 ^ s.v1 ^ s.v2 ^ s.v3
+}
+
+// Intn returns a pseudo-random number in [0, n). It returns 0 for n <= 0.
+func (s *siprng) Intn(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	return int(s.Uint64() % uint64(n))
+}
+
+// Float64 returns a pseudo-random number in [0, 1).
+func (s *siprng) Float64() float64 {
+	return float64(s.Uint64()>>11) / (1 << 53)
+}
@@ -0,0 +1,53 @@
+// Copyright 2013 Beego Authors
+// Copyright 2014 The Macaron Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package captcha
+
+import "testing"
+
+func TestSipRNGDeterministic(t *testing.T) {
+	key := []byte("0123456789012345")
+
+	a := newSipRNG(key)
+	b := newSipRNG(key)
+
+	for i := 0; i < 8; i++ {
+		va, vb := a.Uint64(), b.Uint64()
+		if va != vb {
+			t.Fatalf("same key produced diverging streams at step %d: %d != %d", i, va, vb)
+		}
+	}
+}
+
+func TestSipRNGDiffersByKey(t *testing.T) {
+	a := newSipRNG([]byte("0123456789012345"))
+	b := newSipRNG([]byte("5432109876543210"))
+
+	if a.Uint64() == b.Uint64() {
+		t.Fatal("different keys produced the same first value")
+	}
+}
+
+func TestSipRNGIntnBounds(t *testing.T) {
+	s := newSipRNG([]byte("0123456789012345"))
+	for i := 0; i < 100; i++ {
+		if v := s.Intn(10); v < 0 || v >= 10 {
+			t.Fatalf("Intn(10) returned out-of-range value %d", v)
+		}
+	}
+	if v := s.Intn(0); v != 0 {
+		t.Fatalf("Intn(0) = %d, want 0", v)
+	}
+}
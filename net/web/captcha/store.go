@@ -0,0 +1,31 @@
+// Copyright 2013 Beego Authors
+// Copyright 2014 The Macaron Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package captcha
+
+import "time"
+
+// Store persists a captcha's pending challenge, keyed by id. It is the seam
+// that lets a *Captcha be used outside the Captchaer middleware, or have its
+// state shared across a cluster instead of living in one process's cache.
+type Store interface {
+	// Set stores digits for id, expiring after ttl.
+	Set(id string, digits []byte, ttl time.Duration) error
+	// Get returns the digits stored for id. It returns an error if id is
+	// unknown or has expired.
+	Get(id string) ([]byte, error)
+	// Delete removes any digits stored for id.
+	Delete(id string) error
+}
@@ -0,0 +1,60 @@
+// Copyright 2013 Beego Authors
+// Copyright 2014 The Macaron Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package captcha
+
+import (
+	"fmt"
+	"time"
+
+	"landzero.net/x/net/web/cache"
+)
+
+// CacheStore adapts a landzero.net/x/net/web/cache.Cache into a Store. It is
+// the default Store, wired in automatically by Captchaer.
+type CacheStore struct {
+	cache cache.Cache
+}
+
+// NewCacheStore wraps an existing cache.Cache as a Store.
+func NewCacheStore(c cache.Cache) *CacheStore {
+	return &CacheStore{cache: c}
+}
+
+// Set stores digits, expiring after ttl.
+func (s *CacheStore) Set(id string, digits []byte, ttl time.Duration) error {
+	return s.cache.Put(id, append([]byte{}, digits...), int64(ttl/time.Second))
+}
+
+// Get reads back digits for id, transparently upgrading captchas written
+// before CacheStore existed: those are stored as either a bare
+// digit-offset string (pre-Version) or a challengeEntry value.
+func (s *CacheStore) Get(id string) ([]byte, error) {
+	switch v := s.cache.Get(id).(type) {
+	case []byte:
+		return v, nil
+	case challengeEntry:
+		return encodeChallenge(v.Version, v.Value), nil
+	case string:
+		return encodeChallenge(1, v), nil
+	default:
+		return nil, fmt.Errorf("captcha: no such id %q", id)
+	}
+}
+
+// Delete removes any digits stored for id.
+func (s *CacheStore) Delete(id string) error {
+	return s.cache.Delete(id)
+}
@@ -0,0 +1,119 @@
+// Copyright 2013 Beego Authors
+// Copyright 2014 The Macaron Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package captcha
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store backed by an in-process LRU. It needs nothing
+// wired up, making it the easiest way to use a *Captcha as a standalone
+// library outside of Captchaer.
+type MemoryStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+type memoryEntry struct {
+	id        string
+	digits    []byte
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates a Store that keeps at most maxEntries captchas,
+// evicting the least recently used once full. ttl is the default
+// expiration used when Set is called with ttl <= 0.
+func NewMemoryStore(maxEntries int, ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// Set stores digits, expiring after ttl (or s.ttl if ttl <= 0), evicting the
+// least recently used entry if the store is at capacity.
+func (s *MemoryStore) Set(id string, digits []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ttl <= 0 {
+		ttl = s.ttl
+	}
+	entry := &memoryEntry{
+		id:        id,
+		digits:    append([]byte{}, digits...),
+		expiresAt: time.Now().Add(ttl),
+	}
+
+	if el, ok := s.items[id]; ok {
+		el.Value = entry
+		s.ll.MoveToFront(el)
+		return nil
+	}
+
+	s.items[id] = s.ll.PushFront(entry)
+	if s.maxEntries > 0 && s.ll.Len() > s.maxEntries {
+		s.removeElement(s.ll.Back())
+	}
+	return nil
+}
+
+// Get returns the digits stored for id, evicting it first if it has expired.
+func (s *MemoryStore) Get(id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.items[id]
+	if !ok {
+		return nil, fmt.Errorf("captcha: no such id %q", id)
+	}
+
+	entry := el.Value.(*memoryEntry)
+	if time.Now().After(entry.expiresAt) {
+		s.removeElement(el)
+		return nil, fmt.Errorf("captcha: no such id %q", id)
+	}
+
+	s.ll.MoveToFront(el)
+	return entry.digits, nil
+}
+
+// Delete removes any digits stored for id.
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.items[id]; ok {
+		s.removeElement(el)
+	}
+	return nil
+}
+
+func (s *MemoryStore) removeElement(el *list.Element) {
+	if el == nil {
+		return
+	}
+	s.ll.Remove(el)
+	delete(s.items, el.Value.(*memoryEntry).id)
+}
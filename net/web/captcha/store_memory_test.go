@@ -0,0 +1,81 @@
+// Copyright 2013 Beego Authors
+// Copyright 2014 The Macaron Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package captcha
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreRoundTrip(t *testing.T) {
+	s := NewMemoryStore(10, time.Minute)
+
+	if err := s.Set("id", []byte("abc123"), time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	got, err := s.Get("id")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(got) != "abc123" {
+		t.Fatalf("Get = %q, want %q", got, "abc123")
+	}
+}
+
+func TestMemoryStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	s := NewMemoryStore(2, time.Minute)
+
+	s.Set("a", []byte("1"), time.Minute)
+	s.Set("b", []byte("2"), time.Minute)
+	// Touch "a" so "b" becomes the least recently used entry.
+	if _, err := s.Get("a"); err != nil {
+		t.Fatalf("Get(a): %v", err)
+	}
+	s.Set("c", []byte("3"), time.Minute)
+
+	if _, err := s.Get("b"); err == nil {
+		t.Fatal("expected \"b\" to have been evicted")
+	}
+	if _, err := s.Get("a"); err != nil {
+		t.Fatal("expected \"a\" to survive eviction as the most recently used")
+	}
+	if _, err := s.Get("c"); err != nil {
+		t.Fatal("expected \"c\" to be present")
+	}
+}
+
+func TestMemoryStoreExpires(t *testing.T) {
+	s := NewMemoryStore(10, time.Minute)
+
+	if err := s.Set("id", []byte("abc123"), time.Millisecond); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := s.Get("id"); err == nil {
+		t.Fatal("expected expired entry to be gone")
+	}
+}
+
+func TestMemoryStoreDelete(t *testing.T) {
+	s := NewMemoryStore(10, time.Minute)
+	s.Set("id", []byte("abc123"), time.Minute)
+	s.Delete("id")
+
+	if _, err := s.Get("id"); err == nil {
+		t.Fatal("expected deleted entry to be gone")
+	}
+}
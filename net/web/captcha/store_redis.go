@@ -0,0 +1,65 @@
+// Copyright 2013 Beego Authors
+// Copyright 2014 The Macaron Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package captcha
+
+import (
+	"context"
+	"time"
+)
+
+// RedisClient is the minimal surface RedisStore needs from a Redis client.
+// It is satisfied by a thin adapter around a concrete client (for example
+// github.com/redis/go-redis/v9's *redis.Client, whose Set/Get/Del already
+// have this shape once their Cmd results are unwrapped with .Err()/.Bytes()).
+// Depending on this narrow, locally-defined interface rather than importing
+// a specific client keeps this package free of a third-party dependency
+// that most callers -- who never touch RedisStore -- would otherwise have
+// to carry.
+type RedisClient interface {
+	// Set stores value under key, expiring after ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Get returns the value stored for key.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Del removes the value stored for key.
+	Del(ctx context.Context, key string) error
+}
+
+// RedisStore adapts a RedisClient into a Store, letting captcha state be
+// shared across a cluster instead of living in one instance's memory.
+type RedisStore struct {
+	client RedisClient
+	prefix string
+}
+
+// NewRedisStore wraps client as a Store, namespacing every key under prefix.
+func NewRedisStore(client RedisClient, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Set stores digits, expiring after ttl.
+func (s *RedisStore) Set(id string, digits []byte, ttl time.Duration) error {
+	return s.client.Set(context.Background(), s.prefix+id, digits, ttl)
+}
+
+// Get returns the digits stored for id.
+func (s *RedisStore) Get(id string) ([]byte, error) {
+	return s.client.Get(context.Background(), s.prefix+id)
+}
+
+// Delete removes any digits stored for id.
+func (s *RedisStore) Delete(id string) error {
+	return s.client.Del(context.Background(), s.prefix+id)
+}